@@ -15,11 +15,17 @@ const defaultHost = "localhost:8080"
 type Client struct {
 	baseURL string
 	http    *http.Client
+	token   string
 }
 
 type Config struct {
 	Host       string
 	HTTPClient *http.Client
+
+	// Token, if set, is sent as a "Bearer" token on every request and on
+	// the /ws dial, matching the bearer-token auth the server enforces
+	// when started with --auth-file.
+	Token string
 }
 
 func New(config Config) *Client {
@@ -35,6 +41,7 @@ func New(config Config) *Client {
 	return &Client{
 		baseURL: baseURL,
 		http:    httpClient,
+		token:   config.Token,
 	}
 }
 
@@ -81,23 +88,30 @@ func (r KeypressRequest) ModifierMask() byte {
 }
 
 func (c *Client) SendKeypress(ctx context.Context, req KeypressRequest) error {
-	payload, err := json.Marshal(req)
+	return c.postJSON(ctx, "/keypress", req)
+}
+
+func (c *Client) postJSON(ctx context.Context, path string, body any) error {
+	payload, err := json.Marshal(body)
 	if err != nil {
-		return fmt.Errorf("marshal keypress: %w", err)
+		return fmt.Errorf("marshal %s request: %w", path, err)
 	}
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/keypress", bytes.NewReader(payload))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
 	if err != nil {
-		return fmt.Errorf("build keypress request: %w", err)
+		return fmt.Errorf("build %s request: %w", path, err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
 	resp, err := c.http.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("send keypress request: %w", err)
+		return fmt.Errorf("send %s request: %w", path, err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		return fmt.Errorf("keypress request failed: %s (%s)", resp.Status, string(body))
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s request failed: %s (%s)", path, resp.Status, string(respBody))
 	}
 	return nil
 }
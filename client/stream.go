@@ -0,0 +1,112 @@
+package usbbridge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// StreamCommand is one client->server frame sent over a Stream. Exactly one
+// of Keypress, Chord, or Type should be set, matching Kind.
+type StreamCommand struct {
+	SeqID    string           `json:"seq_id"`
+	Kind     string           `json:"kind"`
+	Keypress *KeypressRequest `json:"keypress,omitempty"`
+	Chord    *Chord           `json:"chord,omitempty"`
+	Type     *TypeRequest     `json:"type,omitempty"`
+}
+
+const (
+	StreamCommandKeypress = "keypress"
+	StreamCommandChord    = "chord"
+	StreamCommandType     = "type"
+)
+
+// StreamEvent is one server->client frame: an ack for a prior StreamCommand,
+// a device attach/detach notification, or a streamed device log line.
+type StreamEvent struct {
+	Kind     string    `json:"kind"`
+	SeqID    string    `json:"seq_id,omitempty"`
+	OK       bool      `json:"ok,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	DeviceID string    `json:"device_id,omitempty"`
+	Line     string    `json:"line,omitempty"`
+	Time     time.Time `json:"time,omitempty"`
+}
+
+const (
+	StreamEventAck            = "ack"
+	StreamEventDeviceAttached = "device_attached"
+	StreamEventDeviceDetached = "device_detached"
+	StreamEventLogLine        = "log_line"
+)
+
+// Stream is a long-lived, bidirectional connection opened by Client.Stream.
+// Callers send commands with Send and receive acks/device events/log lines
+// from Events.
+type Stream struct {
+	conn      *websocket.Conn
+	events    chan StreamEvent
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+}
+
+// Stream opens a persistent /ws connection for low-latency command
+// dispatch and streamed device events, instead of one HTTP request per
+// keypress.
+func (c *Client) Stream(ctx context.Context) (*Stream, error) {
+	wsURL := "ws" + strings.TrimPrefix(c.baseURL, "http") + "/ws"
+	var header http.Header
+	if c.token != "" {
+		header = http.Header{"Authorization": []string{"Bearer " + c.token}}
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, header)
+	if err != nil {
+		return nil, fmt.Errorf("dial usbbridge stream: %w", err)
+	}
+	s := &Stream{conn: conn, events: make(chan StreamEvent, 64)}
+	go s.readLoop()
+	return s, nil
+}
+
+// Send writes a command frame to the stream. Safe for concurrent use.
+func (s *Stream) Send(cmd StreamCommand) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.conn.WriteJSON(cmd)
+}
+
+// Events returns the channel of server->client frames: command acks,
+// device attach/detach notifications, and streamed device log lines. The
+// channel is closed when the stream ends.
+func (s *Stream) Events() <-chan StreamEvent {
+	return s.events
+}
+
+// Close ends the stream.
+func (s *Stream) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		err = s.conn.Close()
+	})
+	return err
+}
+
+func (s *Stream) readLoop() {
+	defer close(s.events)
+	for {
+		var evt StreamEvent
+		if err := s.conn.ReadJSON(&evt); err != nil {
+			return
+		}
+		select {
+		case s.events <- evt:
+		default:
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package usbbridge
+
+import "context"
+
+// TypeRequest is the body of a POST /type request: a UTF-8 string to be
+// translated rune-by-rune into HID usages and sent in order.
+type TypeRequest struct {
+	Text string `json:"text"`
+	// Layout selects the keyboard layout used to translate Text, e.g. "us",
+	// "uk", "de". Defaults to "us" when empty.
+	Layout string `json:"layout,omitempty"`
+}
+
+// Chord is a single step of a POST /keysequence request: a chord such as
+// "ctrl+alt+t" or a named/plain key such as "enter" or "h".
+type Chord struct {
+	Chord string `json:"chord"`
+	// HoldMS, if non-zero, holds the chord down for that many milliseconds
+	// before releasing it, instead of an immediate press-and-release.
+	HoldMS int `json:"hold_ms,omitempty"`
+	// DelayMS, if non-zero, is waited after this chord before the next one.
+	DelayMS int `json:"delay_ms,omitempty"`
+}
+
+// KeySequenceRequest is the body of a POST /keysequence request.
+type KeySequenceRequest struct {
+	Sequence []Chord `json:"sequence"`
+}
+
+// SendString sends s as a POST /type request; the server translates it
+// rune-by-rune using layout (empty for the default "us" layout).
+func (c *Client) SendString(ctx context.Context, s string, layout string) error {
+	return c.postJSON(ctx, "/type", TypeRequest{Text: s, Layout: layout})
+}
+
+// SendSequence sends an ordered list of chords as a POST /keysequence
+// request.
+func (c *Client) SendSequence(ctx context.Context, sequence []Chord) error {
+	return c.postJSON(ctx, "/keysequence", KeySequenceRequest{Sequence: sequence})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	usbbridge "github.com/2opremio/picousbkeybridge/client"
+	"github.com/2opremio/picousbkeybridge/device"
+)
+
+// defaultDeviceID resolves the device used by endpoints that don't name one
+// explicitly (/keypress, /type, /keysequence).
+func defaultDeviceID(manager *device.Manager) (string, error) {
+	id, ok := manager.DefaultDeviceID()
+	if !ok {
+		return "", fmt.Errorf("no usbbridge device connected")
+	}
+	return id, nil
+}
+
+type deviceInfoResponse struct {
+	ID  string `json:"id"`
+	VID string `json:"vid"`
+	PID string `json:"pid"`
+}
+
+type devicesResponse struct {
+	Devices []deviceInfoResponse `json:"devices"`
+}
+
+func newDevicesHandler(manager *device.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		infos := manager.Devices()
+		resp := devicesResponse{Devices: make([]deviceInfoResponse, 0, len(infos))}
+		for _, info := range infos {
+			resp.Devices = append(resp.Devices, deviceInfoResponse{
+				ID:  info.ID,
+				VID: fmt.Sprintf("0x%04X", info.VID),
+				PID: fmt.Sprintf("0x%04X", info.PID),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// newKeypressHandler serves a POST /keypress-shaped endpoint: decode a
+// KeypressRequest, resolve the target device with resolveID, and send it.
+// /keypress and /devices/{id}/keypress share this body and differ only in
+// how the device ID is resolved.
+func newKeypressHandler(manager *device.Manager, sendTimeout time.Duration, resolveID func(*http.Request) (string, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req usbbridge.KeypressRequest
+		if err := decodeStrict(r, &req); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if req.HIDCode == 0 {
+			http.Error(w, "missing hid_code", http.StatusBadRequest)
+			return
+		}
+		id, err := resolveID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		sendCtx, cancel := context.WithTimeout(r.Context(), sendTimeout)
+		defer cancel()
+		if err := manager.Send(sendCtx, id, req.HIDCode, req.ModifierMask()); err != nil {
+			http.Error(w, fmt.Sprintf("send failed: %v", err), http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(keypressResponse{Status: "ok"})
+	}
+}
+
+func newDeviceKeypressHandler(manager *device.Manager, sendTimeout time.Duration) http.HandlerFunc {
+	return newKeypressHandler(manager, sendTimeout, func(r *http.Request) (string, error) {
+		return r.PathValue("id"), nil
+	})
+}
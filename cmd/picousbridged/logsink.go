@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/2opremio/picousbkeybridge/device"
+)
+
+const (
+	defaultLogMaxSizeMB  = 100
+	defaultLogMaxAgeDays = 28
+	defaultLogMaxBackups = 5
+)
+
+// stringSliceFlag collects repeated occurrences of a flag, e.g.
+// -log-sink=stdout -log-sink=filesystem.
+type stringSliceFlag []string
+
+func (f *stringSliceFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringSliceFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+type logSinkOptions struct {
+	file       string
+	maxSizeMB  int
+	maxAgeDays int
+	maxBackups int
+	url        string
+}
+
+func buildSinks(names []string, opts logSinkOptions, logger *slog.Logger) ([]device.Sink, error) {
+	sinks := make([]device.Sink, 0, len(names))
+	for _, name := range names {
+		sink, err := buildSink(name, opts, logger)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+func buildSink(name string, opts logSinkOptions, logger *slog.Logger) (device.Sink, error) {
+	switch name {
+	case "stdout":
+		return device.NewStdoutSink(), nil
+	case "stderr":
+		return device.NewStderrSink(), nil
+	case "filesystem":
+		return device.NewFileSink(device.FileSinkConfig{
+			Path:       opts.file,
+			MaxSizeMB:  opts.maxSizeMB,
+			MaxAgeDays: opts.maxAgeDays,
+			MaxBackups: opts.maxBackups,
+		})
+	case "http":
+		return device.NewHTTPSink(device.HTTPSinkConfig{URL: opts.url}, logger)
+	default:
+		return nil, fmt.Errorf("unknown log sink %q", name)
+	}
+}
+
+func newMetricsHandler(manager *device.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(struct {
+			DroppedLogLinesBySink map[string]uint64 `json:"dropped_log_lines_by_sink"`
+		}{DroppedLogLinesBySink: manager.Metrics()})
+	}
+}
@@ -2,11 +2,8 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"flag"
-	"fmt"
-	"io"
 	"log/slog"
 	"net"
 	"net/http"
@@ -17,7 +14,6 @@ import (
 	"syscall"
 	"time"
 
-	usbbridge "github.com/2opremio/picousbkeybridge/client"
 	"github.com/2opremio/picousbkeybridge/device"
 )
 
@@ -35,6 +31,20 @@ func main() {
 	sendTimeoutSeconds := flag.Int("send-timeout", defaultSendTimeoutS, "Seconds to wait when queueing a keypress")
 	vidFlag := flag.String("vid", defaultVID, "USB VID of the serial adapter (hex)")
 	pidFlag := flag.String("pid", defaultPID, "USB PID of the serial adapter (hex)")
+	var logSinks stringSliceFlag
+	flag.Var(&logSinks, "log-sink", "Device log sink to enable (stdout, stderr, filesystem, http); repeatable, fans out to all given")
+	logFile := flag.String("log-file", "", "Path for the filesystem log sink")
+	logMaxSizeMB := flag.Int("log-max-size", defaultLogMaxSizeMB, "Filesystem log sink: rotate after this many megabytes")
+	logMaxAgeDays := flag.Int("log-max-age", defaultLogMaxAgeDays, "Filesystem log sink: delete rotated files older than this many days")
+	logMaxBackups := flag.Int("log-max-backups", defaultLogMaxBackups, "Filesystem log sink: keep at most this many rotated files")
+	logURL := flag.String("log-url", "", "URL for the HTTP log sink")
+	authFile := flag.String("auth-file", "", "Path to a JSON file of bearer tokens ({token, name, scopes, rate_per_sec}); unset runs the server without authentication")
+	var allowIPs stringSliceFlag
+	flag.Var(&allowIPs, "allow-ip", "IP or CIDR allowed to reach the server; repeatable. Unset allows any address")
+	tlsCert := flag.String("tls-cert", "", "Path to a TLS certificate, used together with -tls-key; serves plaintext HTTP if no -tls-* flag is set")
+	tlsKey := flag.String("tls-key", "", "Path to the TLS certificate's private key")
+	tlsACMEDomain := flag.String("tls-acme-domain", "", "Domain to obtain a TLS certificate for via ACME")
+	tlsSelfSigned := flag.Bool("tls-self-signed", false, "Serve TLS with an ephemeral self-signed certificate")
 	flag.Parse()
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{}))
@@ -51,18 +61,63 @@ func main() {
     os.Exit(1)
   }
 
+	sinks, err := buildSinks(logSinks, logSinkOptions{
+		file:       *logFile,
+		maxSizeMB:  *logMaxSizeMB,
+		maxAgeDays: *logMaxAgeDays,
+		maxBackups: *logMaxBackups,
+		url:        *logURL,
+	}, logger)
+	if err != nil {
+		logger.Error("invalid log sink configuration", "error", err)
+		os.Exit(1)
+	}
+
+	sec := &security{}
+	if *authFile != "" {
+		tokens, err := loadAuthTokens(*authFile)
+		if err != nil {
+			logger.Error("invalid auth file", "error", err)
+			os.Exit(1)
+		}
+		sec.auth = newAuthenticator(tokens, logger)
+		sec.limiter = newRateLimiter()
+	} else {
+		logger.Warn("no -auth-file given, running without authentication")
+	}
+
+	allowedIPs, err := parseAllowedIPs(allowIPs)
+	if err != nil {
+		logger.Error("invalid allow-ip configuration", "error", err)
+		os.Exit(1)
+	}
+
+	tlsConfig, err := buildTLSConfig(tlsOptions{
+		certFile:   *tlsCert,
+		keyFile:    *tlsKey,
+		acmeDomain: *tlsACMEDomain,
+		selfSigned: *tlsSelfSigned,
+	})
+	if err != nil {
+		logger.Error("invalid TLS configuration", "error", err)
+		os.Exit(1)
+	}
+
   manager := device.NewManager(device.Config{
     Logger: logger,
     VID:    vid,
     PID:    pid,
+    Sinks:  sinks,
   })
 	defer manager.Close()
 
 	addr := net.JoinHostPort(*host, strconv.Itoa(*port))
+	handler := ipAllowListMiddleware(allowedIPs, newHandler(manager, time.Duration(*sendTimeoutSeconds)*time.Second, sec))
 	server := &http.Server{
 		Addr:              addr,
-		Handler:           newHandler(manager, time.Duration(*sendTimeoutSeconds)*time.Second),
+		Handler:           handler,
 		ReadHeaderTimeout: 5 * time.Second,
+		TLSConfig:         tlsConfig,
 	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -70,8 +125,12 @@ func main() {
 
 	errCh := make(chan error, 1)
 	go func() {
-		logger.Info("usbbridge server listening", "addr", addr)
-		errCh <- server.ListenAndServe()
+		logger.Info("usbbridge server listening", "addr", addr, "tls", tlsConfig != nil)
+		if tlsConfig != nil {
+			errCh <- server.ListenAndServeTLS("", "")
+		} else {
+			errCh <- server.ListenAndServe()
+		}
 	}()
 
 	select {
@@ -102,38 +161,16 @@ type keypressResponse struct {
 	Status string `json:"status"`
 }
 
-func newHandler(manager *device.Manager, sendTimeout time.Duration) http.Handler {
+func newHandler(manager *device.Manager, sendTimeout time.Duration, sec *security) http.Handler {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/keypress", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
-		var req usbbridge.KeypressRequest
-		decoder := json.NewDecoder(r.Body)
-		decoder.DisallowUnknownFields()
-		if err := decoder.Decode(&req); err != nil {
-			http.Error(w, "invalid JSON body", http.StatusBadRequest)
-			return
-		}
-		if err := decoder.Decode(&struct{}{}); err != io.EOF {
-			http.Error(w, "invalid JSON body", http.StatusBadRequest)
-			return
-		}
-
-		if req.HIDCode == 0 {
-			http.Error(w, "missing hid_code", http.StatusBadRequest)
-			return
-		}
-		sendCtx, cancel := context.WithTimeout(r.Context(), sendTimeout)
-		defer cancel()
-		if err := manager.Send(sendCtx, req.HIDCode, req.ModifierMask()); err != nil {
-			http.Error(w, fmt.Sprintf("send failed: %v", err), http.StatusServiceUnavailable)
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(keypressResponse{Status: "ok"})
-	})
+	mux.HandleFunc("/keypress", sec.protect(newKeypressHandler(manager, sendTimeout, func(r *http.Request) (string, error) {
+		return defaultDeviceID(manager)
+	}), true, scopeKeypress))
+	mux.HandleFunc("/type", sec.protect(newTypeHandler(manager, sendTimeout), true, scopeKeypress))
+	mux.HandleFunc("/keysequence", sec.protect(newKeySequenceHandler(manager, sendTimeout), true, scopeKeypress))
+	mux.HandleFunc("/devices", sec.protect(newDevicesHandler(manager), false, ""))
+	mux.HandleFunc("/devices/{id}/keypress", sec.protect(newDeviceKeypressHandler(manager, sendTimeout), true, scopeKeypress))
+	mux.HandleFunc("/metrics", sec.protect(newMetricsHandler(manager), false, ""))
+	mux.HandleFunc("/ws", sec.protect(newWSHandler(manager, sendTimeout, sec.limiter), false, scopeKeypress))
 	return mux
 }
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// authToken is one entry of the JSON array loaded from --auth-file.
+type authToken struct {
+	Token      string   `json:"token"`
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	RatePerSec float64  `json:"rate_per_sec"`
+}
+
+func loadAuthTokens(path string) ([]authToken, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read auth file %q: %w", path, err)
+	}
+	var tokens []authToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("parse auth file %q: %w", path, err)
+	}
+	for _, t := range tokens {
+		if t.Token == "" {
+			return nil, fmt.Errorf("auth file %q: entry %q has an empty token", path, t.Name)
+		}
+	}
+	return tokens, nil
+}
+
+// authenticator enforces bearer-token auth. A nil *authenticator means auth
+// is disabled (no --auth-file given).
+type authenticator struct {
+	tokens map[string]authToken
+	logger *slog.Logger
+}
+
+func newAuthenticator(tokens []authToken, logger *slog.Logger) *authenticator {
+	byToken := make(map[string]authToken, len(tokens))
+	for _, t := range tokens {
+		byToken[t.Token] = t
+	}
+	return &authenticator{tokens: byToken, logger: logger}
+}
+
+func (a *authenticator) middleware(next http.HandlerFunc) http.HandlerFunc {
+	if a == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		const prefix = "Bearer "
+		if !strings.HasPrefix(header, prefix) {
+			http.Error(w, "missing bearer token", http.StatusUnauthorized)
+			return
+		}
+		caller, ok := a.tokens[strings.TrimPrefix(header, prefix)]
+		if !ok {
+			http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		a.logger.Info("request authenticated", "caller", caller.Name, "path", r.URL.Path)
+		next(w, r.WithContext(withCaller(r.Context(), caller)))
+	}
+}
+
+// rateLimiter enforces a per-token-bucket limit on top of authenticated
+// requests. A nil *rateLimiter disables rate limiting.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*tokenBucket)}
+}
+
+func (rl *rateLimiter) middleware(next http.HandlerFunc) http.HandlerFunc {
+	if rl == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := callerFromContext(r.Context())
+		if !ok || caller.RatePerSec <= 0 {
+			next(w, r)
+			return
+		}
+		if !rl.allow(caller.Token, caller.RatePerSec) {
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// allowStreamCommand applies the same per-token rate limit to commands
+// dispatched over a /ws connection that the HTTP middleware applies to
+// /keypress, /type, and /keysequence -- otherwise a client can bypass the
+// limiter entirely just by switching transports.
+func allowStreamCommand(ctx context.Context, limiter *rateLimiter) bool {
+	if limiter == nil {
+		return true
+	}
+	caller, ok := callerFromContext(ctx)
+	if !ok || caller.RatePerSec <= 0 {
+		return true
+	}
+	return limiter.allow(caller.Token, caller.RatePerSec)
+}
+
+func (rl *rateLimiter) allow(token string, ratePerSec float64) bool {
+	rl.mu.Lock()
+	bucket, ok := rl.buckets[token]
+	if !ok {
+		bucket = newTokenBucket(ratePerSec)
+		rl.buckets[token] = bucket
+	}
+	rl.mu.Unlock()
+	return bucket.allow()
+}
+
+// tokenBucket is a classic leaky/token-bucket rate limiter: it refills at
+// ratePerSec, up to a burst capacity equal to one second's worth of tokens.
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(ratePerSec float64) *tokenBucket {
+	return &tokenBucket{rate: ratePerSec, capacity: ratePerSec, tokens: ratePerSec, last: time.Now()}
+}
+
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// parseAllowedIPs parses a list of IPs or CIDRs given via repeated
+// --allow-ip flags.
+func parseAllowedIPs(values []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(values))
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			if strings.Contains(v, ":") {
+				v += "/128"
+			} else {
+				v += "/32"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --allow-ip value %q: %w", v, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// ipAllowListMiddleware rejects requests whose remote address isn't in
+// allowed. An empty allowed list disables the check.
+func ipAllowListMiddleware(allowed []*net.IPNet, next http.Handler) http.Handler {
+	if len(allowed) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		for _, ipNet := range allowed {
+			if ipNet.Contains(ip) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		http.Error(w, "forbidden", http.StatusForbidden)
+	})
+}
+
+// scopeKeypress is the scope required, when auth is enabled, to reach any
+// route that can inject keystrokes: /keypress, /devices/{id}/keypress,
+// /type, /keysequence, and /ws (which multiplexes the same commands over a
+// persistent connection).
+const scopeKeypress = "keypress"
+
+// hasScope reports whether caller's token was configured with scope.
+func hasScope(caller authToken, scope string) bool {
+	for _, s := range caller.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// requireScope wraps next with a check that the authenticated caller's
+// token lists scope. An empty scope disables the check. If auth is
+// disabled (no caller attached to the request context), the check is also
+// a no-op, consistent with authenticator.middleware itself being a no-op.
+func requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	if scope == "" {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := callerFromContext(r.Context())
+		if ok && !hasScope(caller, scope) {
+			http.Error(w, fmt.Sprintf("token missing required scope %q", scope), http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// security bundles the middleware newHandler composes around each route:
+// auth -> scope -> ratelimit -> handler.
+type security struct {
+	auth    *authenticator
+	limiter *rateLimiter
+}
+
+// protect wraps h with authentication, a requiredScope check (skipped if
+// empty), and, if rateLimited, per-token rate limiting. Any of the three
+// may be a no-op if not configured.
+func (sec *security) protect(h http.HandlerFunc, rateLimited bool, requiredScope string) http.HandlerFunc {
+	wrapped := h
+	if rateLimited {
+		wrapped = sec.limiter.middleware(wrapped)
+	}
+	wrapped = requireScope(requiredScope, wrapped)
+	return sec.auth.middleware(wrapped)
+}
+
+type callerContextKey struct{}
+
+func withCaller(ctx context.Context, caller authToken) context.Context {
+	return context.WithValue(ctx, callerContextKey{}, caller)
+}
+
+func callerFromContext(ctx context.Context) (authToken, bool) {
+	caller, ok := ctx.Value(callerContextKey{}).(authToken)
+	return caller, ok
+}
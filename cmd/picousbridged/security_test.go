@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketAllow(t *testing.T) {
+	b := newTokenBucket(2) // 2/sec, burst capacity 2
+
+	if !b.allow() {
+		t.Fatal("first request should be allowed: bucket starts full")
+	}
+	if !b.allow() {
+		t.Fatal("second request should be allowed: within burst capacity")
+	}
+	if b.allow() {
+		t.Fatal("third immediate request should be rate limited")
+	}
+
+	// Rewind last instead of sleeping, so the refill is exercised
+	// deterministically.
+	b.last = b.last.Add(-500 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("request after a half-second refill window should be allowed")
+	}
+	if b.allow() {
+		t.Fatal("should be rate limited again immediately after consuming the refill")
+	}
+}
+
+func TestAllowStreamCommand(t *testing.T) {
+	if !allowStreamCommand(context.Background(), nil) {
+		t.Fatal("a nil limiter should never rate limit")
+	}
+
+	limiter := newRateLimiter()
+	if !allowStreamCommand(context.Background(), limiter) {
+		t.Fatal("a context with no caller attached should not be rate limited")
+	}
+
+	ctx := withCaller(context.Background(), authToken{Token: "t", RatePerSec: 1})
+	if !allowStreamCommand(ctx, limiter) {
+		t.Fatal("first command within the caller's rate should be allowed")
+	}
+	if allowStreamCommand(ctx, limiter) {
+		t.Fatal("second immediate command should be rate limited, same as the HTTP middleware")
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	next := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	t.Run("empty scope is a no-op", func(t *testing.T) {
+		h := requireScope("", next)
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("no caller in context is a no-op", func(t *testing.T) {
+		h := requireScope(scopeKeypress, next)
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("caller missing the scope is forbidden", func(t *testing.T) {
+		h := requireScope(scopeKeypress, next)
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r = r.WithContext(withCaller(r.Context(), authToken{Token: "t", Scopes: []string{"read"}}))
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("caller with the scope passes through", func(t *testing.T) {
+		h := requireScope(scopeKeypress, next)
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r = r.WithContext(withCaller(r.Context(), authToken{Token: "t", Scopes: []string{"read", scopeKeypress}}))
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+	})
+}
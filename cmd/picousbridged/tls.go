@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const selfSignedCertLifetime = 365 * 24 * time.Hour
+
+// tlsOptions configures how buildTLSConfig decides whether, and how, to
+// serve TLS. At most one of certFile/keyFile, acmeDomain, or selfSigned
+// should be set; certFile/keyFile wins if present.
+type tlsOptions struct {
+	certFile   string
+	keyFile    string
+	acmeDomain string
+	selfSigned bool
+}
+
+// buildTLSConfig returns nil (serve plaintext HTTP) when none of opts is
+// set.
+func buildTLSConfig(opts tlsOptions) (*tls.Config, error) {
+	switch {
+	case opts.certFile != "" && opts.keyFile != "":
+		cert, err := tls.LoadX509KeyPair(opts.certFile, opts.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load TLS cert/key: %w", err)
+		}
+		return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+	case opts.acmeDomain != "":
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(opts.acmeDomain),
+			Cache:      autocert.DirCache("picousbridged-autocert"),
+		}
+		return manager.TLSConfig(), nil
+	case opts.selfSigned:
+		return selfSignedTLSConfig()
+	default:
+		return nil, nil
+	}
+}
+
+// selfSignedTLSConfig generates an ephemeral, in-memory self-signed
+// certificate, for local/dev use when no real certificate is available.
+func selfSignedTLSConfig() (*tls.Config, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generate self-signed key: %w", err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "picousbridged"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(selfSignedCertLifetime),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("create self-signed certificate: %w", err)
+	}
+	cert := tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
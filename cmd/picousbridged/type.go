@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	usbbridge "github.com/2opremio/picousbkeybridge/client"
+	"github.com/2opremio/picousbkeybridge/device"
+	"github.com/2opremio/picousbkeybridge/layout"
+)
+
+func decodeStrict(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		return fmt.Errorf("trailing data after JSON body")
+	}
+	return nil
+}
+
+func newTypeHandler(manager *device.Manager, sendTimeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req usbbridge.TypeRequest
+		if err := decodeStrict(r, &req); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		layoutName := layout.Default
+		if req.Layout != "" {
+			layoutName = layout.Name(req.Layout)
+		}
+		id, err := defaultDeviceID(manager)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		reqCtx := r.Context()
+		for _, ch := range req.Text {
+			code, modifier, err := layout.Translate(layoutName, ch)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("translate rune: %v", err), http.StatusBadRequest)
+				return
+			}
+			err = sendWithTimeout(reqCtx, sendTimeout, func(sendCtx context.Context) error {
+				return manager.Send(sendCtx, id, code, modifier)
+			})
+			if err != nil {
+				http.Error(w, fmt.Sprintf("send failed: %v", err), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(keypressResponse{Status: "ok"})
+	}
+}
+
+func newKeySequenceHandler(manager *device.Manager, sendTimeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req usbbridge.KeySequenceRequest
+		if err := decodeStrict(r, &req); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		id, err := defaultDeviceID(manager)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		reqCtx := r.Context()
+		for _, step := range req.Sequence {
+			code, modifier, err := layout.ParseChord(step.Chord)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("parse chord: %v", err), http.StatusBadRequest)
+				return
+			}
+			if err := sendChordStep(reqCtx, manager, id, code, modifier, step, sendTimeout); err != nil {
+				http.Error(w, fmt.Sprintf("send failed: %v", err), http.StatusServiceUnavailable)
+				return
+			}
+			if step.DelayMS > 0 {
+				if err := sleepCtx(reqCtx, time.Duration(step.DelayMS)*time.Millisecond); err != nil {
+					http.Error(w, fmt.Sprintf("send canceled: %v", err), http.StatusServiceUnavailable)
+					return
+				}
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(keypressResponse{Status: "ok"})
+	}
+}
+
+// sendChordStep presses (and, for a held chord, releases) one step of a key
+// sequence. ctx only bounds the request's overall lifetime (e.g. client
+// disconnect); each individual send gets its own sendTimeout-bounded
+// context so that a chord's hold_ms/delay_ms don't eat into the budget
+// meant for queueing the next packet.
+func sendChordStep(ctx context.Context, manager *device.Manager, id string, code, modifier byte, step usbbridge.Chord, sendTimeout time.Duration) error {
+	if step.HoldMS <= 0 {
+		return sendWithTimeout(ctx, sendTimeout, func(sendCtx context.Context) error {
+			return manager.Send(sendCtx, id, code, modifier)
+		})
+	}
+	err := sendWithTimeout(ctx, sendTimeout, func(sendCtx context.Context) error {
+		return manager.SendEvent(sendCtx, id, code, modifier, device.EventPress)
+	})
+	if err != nil {
+		return err
+	}
+	if err := sleepCtx(ctx, time.Duration(step.HoldMS)*time.Millisecond); err != nil {
+		return err
+	}
+	return sendWithTimeout(ctx, sendTimeout, func(sendCtx context.Context) error {
+		return manager.SendEvent(sendCtx, id, code, modifier, device.EventRelease)
+	})
+}
+
+// sendWithTimeout runs send with a fresh child of ctx bounded by timeout,
+// so that sendTimeout (documented as the budget for queueing a single
+// packet) applies per-send rather than accumulating across a whole
+// /type or /keysequence request.
+func sendWithTimeout(ctx context.Context, timeout time.Duration, send func(context.Context) error) error {
+	sendCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return send(sendCtx)
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	usbbridge "github.com/2opremio/picousbkeybridge/client"
+	"github.com/2opremio/picousbkeybridge/device"
+	"github.com/2opremio/picousbkeybridge/layout"
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// newWSHandler serves /ws: a persistent, bidirectional connection that
+// multiplexes keypress/chord/type commands with device attach/detach
+// notifications and streamed device log lines, avoiding a full HTTP
+// round-trip per key.
+func newWSHandler(manager *device.Manager, sendTimeout time.Duration, limiter *rateLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		outCh := make(chan usbbridge.StreamEvent, 64)
+		sub := manager.Subscribe()
+		defer manager.Unsubscribe(sub)
+		done := make(chan struct{})
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() { defer wg.Done(); writeLoop(conn, outCh, done) }()
+		go func() { defer wg.Done(); forwardDeviceEvents(sub, outCh, done) }()
+
+		readCommands(r.Context(), conn, manager, sendTimeout, outCh, done, limiter)
+		close(done)
+		wg.Wait()
+	}
+}
+
+func writeLoop(conn *websocket.Conn, outCh <-chan usbbridge.StreamEvent, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case evt := <-outCh:
+			if err := conn.WriteJSON(evt); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func forwardDeviceEvents(sub <-chan device.Event, outCh chan<- usbbridge.StreamEvent, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case ev, ok := <-sub:
+			if !ok {
+				return
+			}
+			select {
+			case outCh <- toStreamEvent(ev):
+			case <-done:
+				return
+			}
+		}
+	}
+}
+
+func toStreamEvent(ev device.Event) usbbridge.StreamEvent {
+	evt := usbbridge.StreamEvent{DeviceID: ev.Device.ID, Time: ev.Time}
+	switch ev.Kind {
+	case device.EventDeviceAttached:
+		evt.Kind = usbbridge.StreamEventDeviceAttached
+	case device.EventDeviceDetached:
+		evt.Kind = usbbridge.StreamEventDeviceDetached
+	case device.EventLogLine:
+		evt.Kind = usbbridge.StreamEventLogLine
+		evt.Line = ev.Line
+	}
+	return evt
+}
+
+// readCommands blocks reading command frames until the connection closes,
+// executing each one in turn and sending its ack on outCh before reading
+// the next frame. Commands run on this single goroutine, never concurrently
+// with each other, so a client typing a string or chord sequence over one
+// connection sees its keys applied in the order it sent them.
+func readCommands(ctx context.Context, conn *websocket.Conn, manager *device.Manager, sendTimeout time.Duration, outCh chan<- usbbridge.StreamEvent, done <-chan struct{}, limiter *rateLimiter) {
+	for {
+		var cmd usbbridge.StreamCommand
+		if err := conn.ReadJSON(&cmd); err != nil {
+			return
+		}
+		ack := executeStreamCommand(ctx, manager, sendTimeout, cmd, limiter)
+		select {
+		case outCh <- ack:
+		case <-done:
+			return
+		}
+	}
+}
+
+func executeStreamCommand(ctx context.Context, manager *device.Manager, sendTimeout time.Duration, cmd usbbridge.StreamCommand, limiter *rateLimiter) usbbridge.StreamEvent {
+	ack := usbbridge.StreamEvent{Kind: usbbridge.StreamEventAck, SeqID: cmd.SeqID}
+	if !allowStreamCommand(ctx, limiter) {
+		ack.Error = "rate limit exceeded"
+		return ack
+	}
+	id, err := defaultDeviceID(manager)
+	if err != nil {
+		ack.Error = err.Error()
+		return ack
+	}
+
+	switch cmd.Kind {
+	case usbbridge.StreamCommandKeypress:
+		if cmd.Keypress == nil {
+			ack.Error = "missing keypress"
+			return ack
+		}
+		err = sendWithTimeout(ctx, sendTimeout, func(sendCtx context.Context) error {
+			return manager.Send(sendCtx, id, cmd.Keypress.HIDCode, cmd.Keypress.ModifierMask())
+		})
+	case usbbridge.StreamCommandChord:
+		if cmd.Chord == nil {
+			ack.Error = "missing chord"
+			return ack
+		}
+		var code, modifier byte
+		code, modifier, err = layout.ParseChord(cmd.Chord.Chord)
+		if err == nil {
+			err = sendChordStep(ctx, manager, id, code, modifier, *cmd.Chord, sendTimeout)
+		}
+		if err == nil && cmd.Chord.DelayMS > 0 {
+			err = sleepCtx(ctx, time.Duration(cmd.Chord.DelayMS)*time.Millisecond)
+		}
+	case usbbridge.StreamCommandType:
+		if cmd.Type == nil {
+			ack.Error = "missing type"
+			return ack
+		}
+		layoutName := layout.Default
+		if cmd.Type.Layout != "" {
+			layoutName = layout.Name(cmd.Type.Layout)
+		}
+		for _, r := range cmd.Type.Text {
+			var code, modifier byte
+			code, modifier, err = layout.Translate(layoutName, r)
+			if err == nil {
+				err = sendWithTimeout(ctx, sendTimeout, func(sendCtx context.Context) error {
+					return manager.Send(sendCtx, id, code, modifier)
+				})
+			}
+			if err != nil {
+				break
+			}
+		}
+	default:
+		err = fmt.Errorf("unknown command kind %q", cmd.Kind)
+	}
+
+	if err != nil {
+		ack.Error = err.Error()
+		return ack
+	}
+	ack.OK = true
+	return ack
+}
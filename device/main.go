@@ -8,6 +8,7 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -20,33 +21,176 @@ const (
 	defaultVID         = 0xCafe
 	defaultPID         = 0x4001
 	defaultBaudRate    = 115200
-	usbbridgePacketLen = 2
+	usbbridgePacketLen = 3
+	legacyPacketLen    = 2
 	defaultWriteQueue  = 1
 	maxLogLineBytes    = 4096
+	scanInterval       = 500 * time.Millisecond
+
+	protocolProbeEvent   = 0xFF
+	protocolAckByte      = 0x01
+	protocolProbeTimeout = 200 * time.Millisecond
 )
 
 var errDeviceNotFound = errors.New("usbbridge device not found")
 
-type Manager struct {
-	mu       sync.Mutex
+// PacketEvent selects how a keycode/modifier pair is applied on the device,
+// carried as the third byte of the wire packet. Devices negotiated into
+// legacy two-byte mode only support EventPressAndRelease.
+type PacketEvent byte
+
+const (
+	EventPressAndRelease PacketEvent = 0
+	EventPress           PacketEvent = 1
+	EventRelease         PacketEvent = 2
+)
+
+// DeviceInfo describes one attached Pico bridge. ID is currently the OS
+// serial port path, which is stable for as long as the device stays plugged
+// into the same port.
+type DeviceInfo struct {
+	ID  string
+	VID uint16
+	PID uint16
+}
+
+// Config configures a Manager. VID/PID select which attached USB serial
+// devices are treated as usbbridge devices; both default to the Pico
+// bridge's factory values when zero.
+type Config struct {
+	Logger *slog.Logger
+	VID    uint16
+	PID    uint16
+
+	// Sinks receive every line a device emits over its serial port. When
+	// empty, device output is written to stdout.
+	Sinks []Sink
+}
+
+// device holds the state of a single attached bridge. Its port and
+// compatMode are set once at open time and never mutated afterwards; a
+// reattached device gets a brand-new device value instead.
+type device struct {
+	id         string
+	port       serial.Port
+	compatMode bool
+
+	// readReplay holds any bytes negotiateProtocol consumed from the port
+	// past the ack (or, in legacy mode, before giving up on one) so the
+	// log read loop can feed them into the log pipeline instead of
+	// silently dropping the start of the device's output.
+	readReplay []byte
+
 	writeMu  sync.Mutex
-	port     serial.Port
-	portName string
+	writeCh  chan []byte
 	stopCh   chan struct{}
+	stopOnce sync.Once
 	wg       sync.WaitGroup
-	logger   *slog.Logger
+}
 
-	writeCh chan [usbbridgePacketLen]byte
+// requestStop closes stopCh exactly once, so it can be called concurrently
+// from the read loop, the write worker, and closeDevice without panicking.
+func (d *device) requestStop() {
+	d.stopOnce.Do(func() { close(d.stopCh) })
 }
 
-type Config struct {
-	Logger *slog.Logger
+type attachCallback func(DeviceInfo)
+
+// Manager enumerates, connects to, and multiplexes sends across every
+// attached Pico bridge matching its configured VID/PID.
+type Manager struct {
+	mu      sync.Mutex
+	devices map[string]*device
+	vid     uint16
+	pid     uint16
+
+	loggedNotFound bool
+
+	callbackMu sync.Mutex
+	onAttach   []attachCallback
+	onDetach   []attachCallback
+
+	subsMu sync.Mutex
+	subs   map[chan Event]struct{}
+
+	sinkQueues []*sinkQueue
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+	logger *slog.Logger
+}
+
+// EventKind identifies the kind of Event published on the Manager's event
+// bus.
+type EventKind int
+
+const (
+	EventDeviceAttached EventKind = iota
+	EventDeviceDetached
+	EventLogLine
+)
+
+// Event is published to subscribers (see Subscribe) whenever a device
+// attaches, detaches, or emits a line of serial output. Transports such as
+// the HTTP /ws endpoint and future sinks/metrics consumers all read from the
+// same stream instead of wiring up their own plumbing to the serial worker.
+type Event struct {
+	Kind   EventKind
+	Device DeviceInfo
+	Line   string
+	Time   time.Time
+}
+
+// Subscribe returns a channel of every Event published from now on. The
+// channel is buffered and lossy: a slow consumer misses events rather than
+// blocking the publisher. Callers must Unsubscribe when done.
+func (m *Manager) Subscribe() <-chan Event {
+	ch := make(chan Event, 64)
+	m.subsMu.Lock()
+	m.subs[ch] = struct{}{}
+	m.subsMu.Unlock()
+	return ch
+}
+
+// Unsubscribe stops and closes a channel previously returned by Subscribe.
+func (m *Manager) Unsubscribe(ch <-chan Event) {
+	m.subsMu.Lock()
+	for c := range m.subs {
+		if c == ch {
+			delete(m.subs, c)
+			close(c)
+			break
+		}
+	}
+	m.subsMu.Unlock()
+}
+
+func (m *Manager) publish(ev Event) {
+	m.subsMu.Lock()
+	defer m.subsMu.Unlock()
+	for ch := range m.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
 }
 
 func NewManager(config Config) *Manager {
+	vid := config.VID
+	if vid == 0 {
+		vid = defaultVID
+	}
+	pid := config.PID
+	if pid == 0 {
+		pid = defaultPID
+	}
 	manager := &Manager{
+		devices: make(map[string]*device),
+		subs:    make(map[chan Event]struct{}),
+		vid:     vid,
+		pid:     pid,
 		stopCh:  make(chan struct{}),
-		writeCh: make(chan [usbbridgePacketLen]byte, defaultWriteQueue),
 	}
 	if config.Logger != nil {
 		manager.logger = config.Logger
@@ -54,174 +198,388 @@ func NewManager(config Config) *Manager {
 		manager.logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{}))
 	}
 	manager.logger = manager.logger.With("component", "usbbridge")
-	manager.wg.Go(manager.reconnectLoop)
-	manager.wg.Go(manager.deviceLogReadLoop)
-	manager.wg.Go(manager.writeWorker)
+
+	sinks := config.Sinks
+	if len(sinks) == 0 {
+		sinks = []Sink{NewStdoutSink()}
+	}
+	for i, sink := range sinks {
+		manager.sinkQueues = append(manager.sinkQueues, newSinkQueue(fmt.Sprintf("%T#%d", sink, i), sink, manager.logger))
+	}
+
+	manager.wg.Go(manager.scanLoop)
 	return manager
 }
 
-func (m *Manager) Send(ctx context.Context, keyCode byte, modifier byte) error {
-	if m.currentPort() == nil {
-		return fmt.Errorf("usbbridge not connected")
+// Metrics returns, per configured log sink, the number of lines dropped
+// because the sink's queue was full.
+func (m *Manager) Metrics() map[string]uint64 {
+	metrics := make(map[string]uint64, len(m.sinkQueues))
+	for _, q := range m.sinkQueues {
+		metrics[q.name] = q.droppedCount()
+	}
+	return metrics
+}
+
+// Devices returns a snapshot of currently attached devices, ordered by ID.
+func (m *Manager) Devices() []DeviceInfo {
+	m.mu.Lock()
+	infos := make([]DeviceInfo, 0, len(m.devices))
+	for _, d := range m.devices {
+		infos = append(infos, DeviceInfo{ID: d.id, VID: m.vid, PID: m.pid})
+	}
+	m.mu.Unlock()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// DefaultDeviceID returns the ID of an arbitrary attached device, for
+// callers that don't care which one they talk to. It reports false if no
+// device is attached.
+func (m *Manager) DefaultDeviceID() (string, bool) {
+	infos := m.Devices()
+	if len(infos) == 0 {
+		return "", false
+	}
+	return infos[0].ID, true
+}
+
+// OnAttach registers a callback invoked whenever a device is attached.
+func (m *Manager) OnAttach(cb func(DeviceInfo)) {
+	m.callbackMu.Lock()
+	m.onAttach = append(m.onAttach, cb)
+	m.callbackMu.Unlock()
+}
+
+// OnDetach registers a callback invoked whenever a device is detached.
+func (m *Manager) OnDetach(cb func(DeviceInfo)) {
+	m.callbackMu.Lock()
+	m.onDetach = append(m.onDetach, cb)
+	m.callbackMu.Unlock()
+}
+
+// Send queues a keycode/modifier press-and-release on the device with the
+// given ID, equivalent to SendEvent(ctx, id, keyCode, modifier,
+// EventPressAndRelease).
+func (m *Manager) Send(ctx context.Context, id string, keyCode byte, modifier byte) error {
+	return m.SendEvent(ctx, id, keyCode, modifier, EventPressAndRelease)
+}
+
+// SendEvent queues a single keycode/modifier packet for the given event on
+// the device with the given ID. On a device negotiated into legacy
+// two-byte mode, only EventPressAndRelease is supported.
+func (m *Manager) SendEvent(ctx context.Context, id string, keyCode byte, modifier byte, event PacketEvent) error {
+	d := m.getDevice(id)
+	if d == nil {
+		return fmt.Errorf("usbbridge: device %q not connected", id)
 	}
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	packet := [usbbridgePacketLen]byte{keyCode, modifier}
+	packet, err := buildPacket(d.compatMode, keyCode, modifier, event)
+	if err != nil {
+		return err
+	}
 	select {
-	case m.writeCh <- packet:
+	case d.writeCh <- packet:
 		return nil
-	case <-m.stopCh:
-		return fmt.Errorf("usbbridge closed")
+	case <-d.stopCh:
+		return fmt.Errorf("usbbridge: device %q disconnected", id)
 	case <-ctx.Done():
 		return fmt.Errorf("usbbridge send canceled: %w", ctx.Err())
 	}
 }
 
+func buildPacket(compatMode bool, keyCode byte, modifier byte, event PacketEvent) ([]byte, error) {
+	if compatMode {
+		if event != EventPressAndRelease {
+			return nil, fmt.Errorf("usbbridge: device negotiated legacy protocol, press/release events unsupported")
+		}
+		return []byte{keyCode, modifier}, nil
+	}
+	return []byte{keyCode, modifier, byte(event)}, nil
+}
+
+func (m *Manager) getDevice(id string) *device {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.devices[id]
+}
+
 func (m *Manager) Close() {
-	var port serial.Port
 	close(m.stopCh)
 	m.mu.Lock()
-	port = m.port
-	m.port = nil
-	m.portName = ""
+	devices := make([]*device, 0, len(m.devices))
+	for _, d := range m.devices {
+		devices = append(devices, d)
+	}
+	m.devices = nil
 	m.mu.Unlock()
 
-	if port != nil {
-		_ = port.Close()
+	for _, d := range devices {
+		m.closeDevice(d)
 	}
 	m.wg.Wait()
+
+	for _, q := range m.sinkQueues {
+		q.close()
+	}
+
+	m.subsMu.Lock()
+	for ch := range m.subs {
+		close(ch)
+	}
+	m.subs = nil
+	m.subsMu.Unlock()
 }
 
-func (m *Manager) deviceLogReadLoop() {
-	for {
-		if m.isStopped() {
-			return
-		}
-		port := m.currentPort()
-		if port == nil {
-			select {
-			case <-m.stopCh:
-				return
-			case <-time.After(500 * time.Millisecond):
-			}
-			continue
-		}
-		if err := m.readLogs(port); err != nil {
-			if m.isStopped() {
-				return
-			}
-			if err != io.EOF {
-				m.logger.Warn("log read error", "error", err)
-			}
-			m.disconnectWithLog(err)
-			select {
-			case <-m.stopCh:
-				return
-			case <-time.After(500 * time.Millisecond):
-			}
-		}
+func (m *Manager) isStopped() bool {
+	select {
+	case <-m.stopCh:
+		return true
+	default:
+		return false
 	}
 }
 
-func (m *Manager) writeWorker() {
+// scanLoop periodically enumerates serial ports and diffs them against the
+// currently open devices, opening newly matching ports and closing ones
+// that disappeared.
+func (m *Manager) scanLoop() {
 	for {
+		if m.isStopped() {
+			return
+		}
+		m.scanOnce()
 		select {
 		case <-m.stopCh:
 			return
-		case packet := <-m.writeCh:
-			port := m.currentPort()
-			if port == nil {
-				continue
-			}
-			if err := m.writePacket(port, packet[:]); err != nil {
-				if !m.isStopped() {
-					m.logger.Warn("write failed", "error", err)
-				}
-			}
+		case <-time.After(scanInterval):
 		}
 	}
 }
 
-func (m *Manager) reconnectLoop() {
-	var lastErr string
-	var loggedNotFound bool
-	for {
-		if m.isStopped() {
-			return
-		}
-		if m.currentPort() != nil {
-			select {
-			case <-m.stopCh:
-				return
-			case <-time.After(500 * time.Millisecond):
-			}
+func (m *Manager) scanOnce() {
+	ports, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		m.logger.Warn("enumerate serial ports", "error", err)
+		return
+	}
+
+	expectedVID := fmt.Sprintf("%04X", m.vid)
+	expectedPID := fmt.Sprintf("%04X", m.pid)
+	seen := make(map[string]bool)
+	for _, port := range ports {
+		if port == nil || !port.IsUSB {
 			continue
 		}
-		err := m.connect()
-		if err != nil {
-			lastErr, loggedNotFound = m.handleConnectError(err, lastErr, loggedNotFound)
-			if !m.sleepUntilRetry(1 * time.Second) {
-				return
-			}
+		if !strings.EqualFold(port.VID, expectedVID) || !strings.EqualFold(port.PID, expectedPID) {
 			continue
 		}
-		lastErr = ""
-		loggedNotFound = false
+		seen[port.Name] = true
+		if !m.hasDevice(port.Name) {
+			m.openDevice(port.Name)
+		}
 	}
-}
+	m.logNotFoundIfNeeded(len(seen))
 
-func (m *Manager) handleConnectError(err error, lastErr string, loggedNotFound bool) (string, bool) {
-	errMsg := err.Error()
-	if errors.Is(err, errDeviceNotFound) {
-		if !loggedNotFound {
-			m.logger.Warn("device not found", "vid", fmt.Sprintf("0x%04X", defaultVID), "pid", fmt.Sprintf("0x%04X", defaultPID))
-			loggedNotFound = true
+	var stale []*device
+	m.mu.Lock()
+	for name, d := range m.devices {
+		if !seen[name] {
+			stale = append(stale, d)
 		}
-	} else if errMsg != lastErr {
-		m.logger.Warn("connect failed", "error", err)
 	}
-	return errMsg, loggedNotFound
+	m.mu.Unlock()
+	for _, d := range stale {
+		m.removeDevice(d)
+	}
 }
 
-func (m *Manager) sleepUntilRetry(delay time.Duration) bool {
-	select {
-	case <-m.stopCh:
-		return false
-	case <-time.After(delay):
-		return true
+func (m *Manager) logNotFoundIfNeeded(matchCount int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if matchCount > 0 {
+		m.loggedNotFound = false
+		return
 	}
+	if len(m.devices) > 0 || m.loggedNotFound {
+		return
+	}
+	m.loggedNotFound = true
+	m.logger.Warn("device not found", "vid", fmt.Sprintf("0x%04X", m.vid), "pid", fmt.Sprintf("0x%04X", m.pid), "error", errDeviceNotFound)
 }
 
-func (m *Manager) isStopped() bool {
-	select {
-	case <-m.stopCh:
-		return true
-	default:
-		return false
+func (m *Manager) hasDevice(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.devices[id]
+	return ok
+}
+
+func (m *Manager) openDevice(portName string) {
+	port, err := serial.Open(portName, &serial.Mode{BaudRate: defaultBaudRate})
+	if err != nil {
+		m.logger.Warn("open usbbridge port failed", "port", portName, "error", err)
+		return
+	}
+	if err := port.SetDTR(true); err != nil {
+		m.logger.Warn("set DTR failed", "port", portName, "error", err)
+	}
+	compatMode, replay := negotiateProtocol(port, m.logger)
+	if compatMode {
+		m.logger.Warn("usbbridge device did not ack protocol probe, falling back to legacy 2-byte packets", "port", portName)
+	}
+
+	d := &device{
+		id:         portName,
+		port:       port,
+		compatMode: compatMode,
+		writeCh:    make(chan []byte, defaultWriteQueue),
+		stopCh:     make(chan struct{}),
+		readReplay: replay,
+	}
+
+	m.mu.Lock()
+	if m.isStopped() {
+		m.mu.Unlock()
+		_ = port.Close()
+		return
+	}
+	m.devices[portName] = d
+	m.mu.Unlock()
+
+	d.wg.Go(func() { m.deviceReadLoop(d) })
+	d.wg.Go(func() { m.deviceWriteWorker(d) })
+	m.wg.Go(func() { m.watchDevice(d) })
+
+	m.logger.Info("device attached", "port", portName)
+	m.fireAttach(DeviceInfo{ID: d.id, VID: m.vid, PID: m.pid})
+}
+
+// negotiateProtocol probes a freshly opened port for 3-byte packet support
+// before it is published to the read/write workers. It reports whether the
+// device must be driven in legacy 2-byte compatibility mode, plus any bytes
+// it read off the port beyond the ack itself (or, if no ack ever came, the
+// bytes it read while waiting for one). A device that doesn't speak the
+// probe protocol is emitting its normal boot/log output right at this
+// point, so those bytes are real log data, not noise to discard; the
+// caller must replay them into the log pipeline.
+func negotiateProtocol(port serial.Port, logger *slog.Logger) (compatMode bool, replay []byte) {
+	probe := []byte{0, 0, protocolProbeEvent}
+	if _, err := port.Write(probe); err != nil {
+		return true, nil
 	}
+	if err := port.SetReadTimeout(protocolProbeTimeout); err != nil {
+		logger.Warn("set read timeout failed", "error", err)
+		return true, nil
+	}
+	buf := make([]byte, 64)
+	n, err := port.Read(buf)
+	if err != nil || n == 0 {
+		return true, nil
+	}
+	if buf[0] == protocolAckByte {
+		return false, append([]byte(nil), buf[1:n]...)
+	}
+	return true, append([]byte(nil), buf[:n]...)
 }
 
-func (m *Manager) currentPort() serial.Port {
+// removeDevice detaches d, but only if it is still the device currently
+// registered under its ID; this makes it safe to call concurrently from
+// both watchDevice and the scan loop without double-closing or
+// double-notifying.
+func (m *Manager) removeDevice(d *device) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	return m.port
+	existing, ok := m.devices[d.id]
+	if !ok || existing != d {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.devices, d.id)
+	m.mu.Unlock()
+
+	m.closeDevice(d)
+	m.logger.Warn("device detached", "port", d.id)
+	m.fireDetach(DeviceInfo{ID: d.id, VID: m.vid, PID: m.pid})
 }
 
-func (m *Manager) readLogs(port serial.Port) error {
+func (m *Manager) closeDevice(d *device) {
+	d.requestStop()
+	_ = d.port.Close()
+	d.wg.Wait()
+}
+
+// watchDevice waits for d to be asked to stop, then removes it. It must run
+// outside d.wg: d's own read loop and write worker signal a stop by closing
+// d.stopCh but never wait on d.wg themselves, since closeDevice (called from
+// removeDevice) blocks on d.wg.Wait() and a goroutine can't wait on a
+// WaitGroup that is tracking its own completion.
+func (m *Manager) watchDevice(d *device) {
+	<-d.stopCh
+	m.removeDevice(d)
+}
+
+func (m *Manager) fireAttach(info DeviceInfo) {
+	m.callbackMu.Lock()
+	callbacks := append([]attachCallback(nil), m.onAttach...)
+	m.callbackMu.Unlock()
+	for _, cb := range callbacks {
+		cb(info)
+	}
+	m.publish(Event{Kind: EventDeviceAttached, Device: info, Time: time.Now()})
+}
+
+func (m *Manager) fireDetach(info DeviceInfo) {
+	m.callbackMu.Lock()
+	callbacks := append([]attachCallback(nil), m.onDetach...)
+	m.callbackMu.Unlock()
+	for _, cb := range callbacks {
+		cb(info)
+	}
+	m.publish(Event{Kind: EventDeviceDetached, Device: info, Time: time.Now()})
+}
+
+func (m *Manager) deviceReadLoop(d *device) {
+	if err := m.readLogs(d); err != nil && err != io.EOF {
+		m.logger.Warn("log read error", "port", d.id, "error", err)
+	}
+	d.requestStop()
+}
+
+func (m *Manager) deviceWriteWorker(d *device) {
+	for {
+		select {
+		case <-d.stopCh:
+			return
+		case packet := <-d.writeCh:
+			if err := m.writePacket(d, packet); err != nil {
+				m.logger.Warn("write failed", "port", d.id, "error", err)
+				d.requestStop()
+			}
+		}
+	}
+}
+
+func (m *Manager) readLogs(d *device) error {
 	state := logLineState{}
+	if len(d.readReplay) > 0 {
+		m.consumeLogBytes(d, &state, d.readReplay)
+	}
 	readBuf := make([]byte, 256)
 	for {
-		n, err := port.Read(readBuf)
+		select {
+		case <-d.stopCh:
+			return io.EOF
+		default:
+		}
+		n, err := d.port.Read(readBuf)
 		if n > 0 {
-			m.consumeLogBytes(&state, readBuf[:n])
+			m.consumeLogBytes(d, &state, readBuf[:n])
 		}
 		if err != nil {
 			return err
 		}
-		if n == 0 && m.isStopped() {
-			return io.EOF
-		}
 	}
 }
 
@@ -230,12 +588,12 @@ type logLineState struct {
 	truncated bool
 }
 
-func (m *Manager) consumeLogBytes(state *logLineState, data []byte) {
+func (m *Manager) consumeLogBytes(d *device, state *logLineState, data []byte) {
 	for len(data) > 0 {
 		line, rest, found := splitAtNewline(data)
-		m.appendLogBytes(state, line)
+		m.appendLogBytes(d, state, line)
 		if found {
-			m.flushLogLine(state)
+			m.flushLogLine(d, state)
 			state.truncated = false
 			data = rest
 			continue
@@ -252,11 +610,11 @@ func splitAtNewline(data []byte) ([]byte, []byte, bool) {
 	return data[:index], data[index+1:], true
 }
 
-func (m *Manager) appendLogBytes(state *logLineState, data []byte) {
+func (m *Manager) appendLogBytes(d *device, state *logLineState, data []byte) {
 	for len(data) > 0 {
 		space := maxLogLineBytes - state.buffer.Len()
 		if space == 0 {
-			m.flushTruncatedLine(state)
+			m.flushTruncatedLine(d, state)
 			continue
 		}
 		toWrite := space
@@ -266,133 +624,47 @@ func (m *Manager) appendLogBytes(state *logLineState, data []byte) {
 		_, _ = state.buffer.Write(data[:toWrite])
 		data = data[toWrite:]
 		if state.buffer.Len() == maxLogLineBytes {
-			m.flushTruncatedLine(state)
+			m.flushTruncatedLine(d, state)
 		}
 	}
 }
 
-func (m *Manager) flushTruncatedLine(state *logLineState) {
-	m.logDeviceLine(state.buffer.Bytes())
+func (m *Manager) flushTruncatedLine(d *device, state *logLineState) {
+	m.logDeviceLine(d, state.buffer.Bytes())
 	state.buffer.Reset()
 	if !state.truncated {
-		m.logger.Warn("usbbridge device log line too long, truncated", "max_bytes", maxLogLineBytes)
+		m.logger.Warn("usbbridge device log line too long, truncated", "port", d.id, "max_bytes", maxLogLineBytes)
 		state.truncated = true
 	}
 }
 
-func (m *Manager) flushLogLine(state *logLineState) {
-	m.logDeviceLine(state.buffer.Bytes())
+func (m *Manager) flushLogLine(d *device, state *logLineState) {
+	m.logDeviceLine(d, state.buffer.Bytes())
 	state.buffer.Reset()
 }
 
-func (m *Manager) logDeviceLine(line []byte) {
+func (m *Manager) logDeviceLine(d *device, line []byte) {
 	text := strings.TrimRight(string(line), "\r")
-	m.logger.Info("usbbridge device", "line", text)
-}
-
-func (m *Manager) writePacketWithTimeout(port serial.Port, packet []byte) error {
-	if _, err := port.Write(packet); err != nil {
-		m.disconnectWithLog(err)
-		return fmt.Errorf("usbbridge write failed: %w", err)
+	now := time.Now()
+	entry := logLine{line: []byte(fmt.Sprintf("[%s] %s", d.id, text)), ts: now}
+	for _, q := range m.sinkQueues {
+		q.enqueue(entry)
 	}
-	return nil
+	m.publish(Event{Kind: EventLogLine, Device: DeviceInfo{ID: d.id, VID: m.vid, PID: m.pid}, Line: text, Time: now})
 }
 
-func (m *Manager) findPort() (string, error) {
-	ports, err := enumerator.GetDetailedPortsList()
-	if err != nil {
-		return "", fmt.Errorf("enumerate serial ports: %w", err)
+func (m *Manager) writePacket(d *device, packet []byte) error {
+	wantLen := usbbridgePacketLen
+	if d.compatMode {
+		wantLen = legacyPacketLen
 	}
-
-	expectedVID := fmt.Sprintf("%04X", defaultVID)
-	expectedPID := fmt.Sprintf("%04X", defaultPID)
-	for _, port := range ports {
-		if port == nil || !port.IsUSB {
-			continue
-		}
-		if !strings.EqualFold(port.VID, expectedVID) || !strings.EqualFold(port.PID, expectedPID) {
-			continue
-		}
-		return port.Name, nil
-	}
-
-	return "", fmt.Errorf("%w (vid=0x%04X pid=0x%04X)", errDeviceNotFound, defaultVID, defaultPID)
-}
-
-func (m *Manager) writePacket(port serial.Port, packet []byte) error {
-	m.mu.Lock()
-	if m.port == nil || m.port != port {
-		m.mu.Unlock()
-		return fmt.Errorf("usbbridge port not connected")
-	}
-	m.mu.Unlock()
-	if len(packet) != usbbridgePacketLen {
+	if len(packet) != wantLen {
 		return fmt.Errorf("invalid usbbridge packet length: %d", len(packet))
 	}
-	m.writeMu.Lock()
-	defer m.writeMu.Unlock()
-	return m.writePacketWithTimeout(port, packet)
-}
-
-func (m *Manager) connect() error {
-	m.mu.Lock()
-	if m.port != nil {
-		m.mu.Unlock()
-		return nil
-	}
-	m.mu.Unlock()
-	if m.isStopped() {
-		return nil
-	}
-
-	portName, err := m.findPort()
-	if err != nil {
-		return err
-	}
-	port, err := serial.Open(portName, &serial.Mode{BaudRate: defaultBaudRate})
-	if err != nil {
-		return fmt.Errorf("open usbbridge port %q: %w", portName, err)
-	}
-	if err := port.SetDTR(true); err != nil {
-		m.logger.Warn("set DTR failed", "error", err)
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+	if _, err := d.port.Write(packet); err != nil {
+		return fmt.Errorf("usbbridge write failed: %w", err)
 	}
-	m.setPort(port, portName)
-	m.logger.Info("connected", "port", portName)
 	return nil
 }
-
-func (m *Manager) setPort(port serial.Port, name string) {
-	m.mu.Lock()
-	if m.isStopped() {
-		m.mu.Unlock()
-		_ = port.Close()
-		return
-	}
-	m.port = port
-	m.portName = name
-	m.mu.Unlock()
-}
-
-func (m *Manager) disconnectWithLog(err error) {
-	m.disconnectWithOptions(err, true)
-}
-
-func (m *Manager) disconnectWithOptions(err error, logError bool) {
-	var port serial.Port
-	m.mu.Lock()
-	if m.port == nil {
-		m.mu.Unlock()
-		return
-	}
-	port = m.port
-	m.port = nil
-	m.portName = ""
-	m.mu.Unlock()
-
-	if port != nil {
-		_ = port.Close()
-	}
-	if logError && !m.isStopped() {
-		m.logger.Warn("disconnected", "error", err)
-	}
-}
@@ -0,0 +1,218 @@
+package device
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+func TestBuildPacket(t *testing.T) {
+	tests := []struct {
+		name       string
+		compatMode bool
+		keyCode    byte
+		modifier   byte
+		event      PacketEvent
+		want       []byte
+		wantErr    bool
+	}{
+		{name: "standard mode press-and-release", keyCode: 0x04, modifier: 0x02, event: EventPressAndRelease, want: []byte{0x04, 0x02, 0x00}},
+		{name: "standard mode press", keyCode: 0x04, modifier: 0x00, event: EventPress, want: []byte{0x04, 0x00, 0x01}},
+		{name: "standard mode release", keyCode: 0x04, modifier: 0x00, event: EventRelease, want: []byte{0x04, 0x00, 0x02}},
+		{name: "legacy mode press-and-release", compatMode: true, keyCode: 0x04, modifier: 0x02, event: EventPressAndRelease, want: []byte{0x04, 0x02}},
+		{name: "legacy mode press unsupported", compatMode: true, keyCode: 0x04, event: EventPress, wantErr: true},
+		{name: "legacy mode release unsupported", compatMode: true, keyCode: 0x04, event: EventRelease, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildPacket(tt.compatMode, tt.keyCode, tt.modifier, tt.event)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("buildPacket(...) = nil error, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildPacket(...) returned error: %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("buildPacket(...) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitAtNewline(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantLine  string
+		wantRest  string
+		wantFound bool
+	}{
+		{name: "no newline", data: "partial line", wantLine: "partial line", wantFound: false},
+		{name: "newline at end", data: "hello\n", wantLine: "hello", wantFound: true},
+		{name: "newline mid-buffer leaves rest", data: "hello\nworld", wantLine: "hello", wantRest: "world", wantFound: true},
+		{name: "leading newline is an empty line", data: "\nhello", wantLine: "", wantRest: "hello", wantFound: true},
+		{name: "empty input", data: "", wantLine: "", wantFound: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, rest, found := splitAtNewline([]byte(tt.data))
+			if found != tt.wantFound {
+				t.Fatalf("splitAtNewline(%q) found = %v, want %v", tt.data, found, tt.wantFound)
+			}
+			if string(line) != tt.wantLine {
+				t.Errorf("splitAtNewline(%q) line = %q, want %q", tt.data, line, tt.wantLine)
+			}
+			if string(rest) != tt.wantRest {
+				t.Errorf("splitAtNewline(%q) rest = %q, want %q", tt.data, rest, tt.wantRest)
+			}
+		})
+	}
+}
+
+// fakePort is a minimal serial.Port fake driving negotiateProtocol's read
+// side: writes are recorded, reads are served from a fixed response (or
+// fail, if readErr is set).
+type fakePort struct {
+	serial.Port
+
+	writes   [][]byte
+	writeErr error
+
+	response []byte
+	readErr  error
+}
+
+func (f *fakePort) Write(p []byte) (int, error) {
+	if f.writeErr != nil {
+		return 0, f.writeErr
+	}
+	f.writes = append(f.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (f *fakePort) Read(p []byte) (int, error) {
+	if f.readErr != nil {
+		return 0, f.readErr
+	}
+	n := copy(p, f.response)
+	return n, nil
+}
+
+func (f *fakePort) SetReadTimeout(t time.Duration) error { return nil }
+
+func TestNegotiateProtocol(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("probe write fails falls back to legacy", func(t *testing.T) {
+		port := &fakePort{writeErr: errors.New("write failed")}
+		compatMode, replay := negotiateProtocol(port, logger)
+		if !compatMode {
+			t.Fatal("compatMode = false, want true when the probe write fails")
+		}
+		if replay != nil {
+			t.Errorf("replay = %v, want nil", replay)
+		}
+	})
+
+	t.Run("no response falls back to legacy", func(t *testing.T) {
+		port := &fakePort{readErr: errors.New("timeout")}
+		compatMode, replay := negotiateProtocol(port, logger)
+		if !compatMode {
+			t.Fatal("compatMode = false, want true when nothing is read back")
+		}
+		if replay != nil {
+			t.Errorf("replay = %v, want nil", replay)
+		}
+	})
+
+	t.Run("ack byte switches to standard mode and replays trailing bytes", func(t *testing.T) {
+		port := &fakePort{response: []byte{protocolAckByte, 'h', 'i'}}
+		compatMode, replay := negotiateProtocol(port, logger)
+		if compatMode {
+			t.Fatal("compatMode = true, want false on a valid ack")
+		}
+		if !bytes.Equal(replay, []byte("hi")) {
+			t.Errorf("replay = %q, want %q", replay, "hi")
+		}
+		if len(port.writes) != 1 || !bytes.Equal(port.writes[0], []byte{0, 0, protocolProbeEvent}) {
+			t.Errorf("writes = %v, want a single probe packet", port.writes)
+		}
+	})
+
+	t.Run("non-ack byte falls back to legacy and replays everything read", func(t *testing.T) {
+		port := &fakePort{response: []byte("boot log line\n")}
+		compatMode, replay := negotiateProtocol(port, logger)
+		if !compatMode {
+			t.Fatal("compatMode = false, want true when the device never acks")
+		}
+		if !bytes.Equal(replay, []byte("boot log line\n")) {
+			t.Errorf("replay = %q, want the full bytes read off the port so no boot output is dropped", replay)
+		}
+	})
+}
+
+func TestManagerSubscribePublishUnsubscribe(t *testing.T) {
+	m := NewManager(Config{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	defer m.Close()
+
+	ch := m.Subscribe()
+	want := Event{Kind: EventLogLine, Line: "hello"}
+	m.publish(want)
+
+	select {
+	case got := <-ch:
+		if got.Kind != want.Kind || got.Line != want.Line {
+			t.Fatalf("received %+v, want %+v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscriber did not receive the published event")
+	}
+
+	m.Unsubscribe(ch)
+	if _, ok := <-ch; ok {
+		t.Fatal("channel should be closed after Unsubscribe")
+	}
+}
+
+func TestManagerPublishIsLossyNotBlocking(t *testing.T) {
+	m := NewManager(Config{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	defer m.Close()
+
+	ch := m.Subscribe()
+	const subscriberBuffer = 64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < subscriberBuffer*2; i++ {
+			m.publish(Event{Kind: EventLogLine, Line: "x"})
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a slow subscriber instead of dropping events")
+	}
+
+	drained := 0
+	for {
+		select {
+		case <-ch:
+			drained++
+			continue
+		default:
+		}
+		break
+	}
+	if drained > subscriberBuffer {
+		t.Errorf("drained %d events, want at most the subscriber's buffer size (%d)", drained, subscriberBuffer)
+	}
+}
@@ -0,0 +1,369 @@
+package device
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sink receives lines the Pico emits over its serial port, one call per
+// line; a line longer than maxLogLineBytes arrives as several calls.
+// Implementations must be safe for concurrent use.
+type Sink interface {
+	Write(line []byte, ts time.Time) error
+	Close() error
+}
+
+const (
+	defaultSinkQueueSize  = 256
+	defaultHTTPBatchSize  = 50
+	defaultHTTPFlushEvery = time.Second
+	defaultHTTPTimeout    = 5 * time.Second
+	httpSinkMaxRetries    = 3
+	httpSinkBaseBackoff   = 200 * time.Millisecond
+)
+
+// WriterSink writes lines to an io.Writer such as os.Stdout or os.Stderr.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func NewStdoutSink() *WriterSink { return NewWriterSink(os.Stdout) }
+func NewStderrSink() *WriterSink { return NewWriterSink(os.Stderr) }
+
+func (s *WriterSink) Write(line []byte, ts time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := fmt.Fprintf(s.w, "%s %s\n", ts.Format(time.RFC3339Nano), line)
+	return err
+}
+
+func (s *WriterSink) Close() error { return nil }
+
+// FileSinkConfig configures a rotating filesystem sink, in the same spirit
+// as lumberjack: roll over at MaxSizeMB, keep at most MaxBackups rotated
+// files, and prune any older than MaxAgeDays.
+type FileSinkConfig struct {
+	Path       string
+	MaxSizeMB  int
+	MaxAgeDays int
+	MaxBackups int
+}
+
+// FileSink is a rotating filesystem Sink.
+type FileSink struct {
+	mu   sync.Mutex
+	cfg  FileSinkConfig
+	file *os.File
+	size int64
+}
+
+func NewFileSink(cfg FileSinkConfig) (*FileSink, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("filesystem log sink: path is required")
+	}
+	s := &FileSink{cfg: cfg}
+	if err := s.openCurrent(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) openCurrent() error {
+	f, err := os.OpenFile(s.cfg.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file %q: %w", s.cfg.Path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("stat log file %q: %w", s.cfg.Path, err)
+	}
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+func (s *FileSink) Write(line []byte, ts time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	formatted := fmt.Appendf(nil, "%s %s\n", ts.Format(time.RFC3339Nano), line)
+	maxSize := int64(s.cfg.MaxSizeMB) * 1024 * 1024
+	if maxSize > 0 && s.size+int64(len(formatted)) > maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(formatted)
+	s.size += int64(n)
+	return err
+}
+
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("close log file %q: %w", s.cfg.Path, err)
+	}
+	rotated := fmt.Sprintf("%s.%s", s.cfg.Path, time.Now().Format("20060102T150405.000000000"))
+	if err := os.Rename(s.cfg.Path, rotated); err != nil {
+		return fmt.Errorf("rotate log file %q: %w", s.cfg.Path, err)
+	}
+	if err := s.openCurrent(); err != nil {
+		return err
+	}
+	s.pruneBackups()
+	return nil
+}
+
+func (s *FileSink) pruneBackups() {
+	if s.cfg.MaxBackups <= 0 && s.cfg.MaxAgeDays <= 0 {
+		return
+	}
+	backups, err := filepath.Glob(s.cfg.Path + ".*")
+	if err != nil {
+		return
+	}
+	sort.Strings(backups)
+	if s.cfg.MaxBackups > 0 && len(backups) > s.cfg.MaxBackups {
+		remove := backups[:len(backups)-s.cfg.MaxBackups]
+		backups = backups[len(backups)-s.cfg.MaxBackups:]
+		for _, name := range remove {
+			_ = os.Remove(name)
+		}
+	}
+	if s.cfg.MaxAgeDays > 0 {
+		cutoff := time.Now().Add(-time.Duration(s.cfg.MaxAgeDays) * 24 * time.Hour)
+		for _, name := range backups {
+			info, err := os.Stat(name)
+			if err == nil && info.ModTime().Before(cutoff) {
+				_ = os.Remove(name)
+			}
+		}
+	}
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// HTTPSinkConfig configures a sink that batches lines and POSTs them as JSON
+// to a URL, with retry/backoff on failure.
+type HTTPSinkConfig struct {
+	URL           string
+	BatchSize     int
+	FlushInterval time.Duration
+	Client        *http.Client
+}
+
+type httpLogLine struct {
+	Line string    `json:"line"`
+	Time time.Time `json:"time"`
+}
+
+// HTTPSink is a batching, retrying Sink that forwards lines to an HTTP
+// endpoint.
+type HTTPSink struct {
+	cfg    HTTPSinkConfig
+	client *http.Client
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	batch  []httpLogLine
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewHTTPSink(cfg HTTPSinkConfig, logger *slog.Logger) (*HTTPSink, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("http log sink: url is required")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultHTTPBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultHTTPFlushEvery
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	s := &HTTPSink{
+		cfg:    cfg,
+		client: cfg.Client,
+		logger: logger,
+		stopCh: make(chan struct{}),
+	}
+	s.wg.Go(s.flushLoop)
+	return s, nil
+}
+
+func (s *HTTPSink) Write(line []byte, ts time.Time) error {
+	s.mu.Lock()
+	s.batch = append(s.batch, httpLogLine{Line: string(line), Time: ts})
+	full := len(s.batch) >= s.cfg.BatchSize
+	s.mu.Unlock()
+	if full {
+		s.flush()
+	}
+	return nil
+}
+
+func (s *HTTPSink) flushLoop() {
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			s.flush()
+			return
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
+
+func (s *HTTPSink) flush() {
+	s.mu.Lock()
+	if len(s.batch) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	batch := s.batch
+	s.batch = nil
+	s.mu.Unlock()
+
+	if err := s.postWithRetry(batch); err != nil {
+		s.logger.Warn("http log sink: send failed", "url", s.cfg.URL, "error", err)
+	}
+}
+
+func (s *HTTPSink) postWithRetry(batch []httpLogLine) error {
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("marshal batch: %w", err)
+	}
+	backoff := httpSinkBaseBackoff
+	var lastErr error
+	for attempt := 0; attempt < httpSinkMaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-s.stopCh:
+				return lastErr
+			}
+			backoff *= 2
+		}
+		req, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("build request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := s.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return lastErr
+}
+
+func (s *HTTPSink) Close() error {
+	close(s.stopCh)
+	s.wg.Wait()
+	return nil
+}
+
+// sinkQueue fans out device log lines to a Sink through a bounded, drop-
+// oldest queue, so a slow or stuck sink (e.g. a stalled HTTP endpoint)
+// cannot block serial reads.
+type sinkQueue struct {
+	name    string
+	sink    Sink
+	ch      chan logLine
+	dropped atomic.Uint64
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+	logger  *slog.Logger
+}
+
+type logLine struct {
+	line []byte
+	ts   time.Time
+}
+
+func newSinkQueue(name string, sink Sink, logger *slog.Logger) *sinkQueue {
+	q := &sinkQueue{
+		name:   name,
+		sink:   sink,
+		ch:     make(chan logLine, defaultSinkQueueSize),
+		stopCh: make(chan struct{}),
+		logger: logger,
+	}
+	q.wg.Go(q.run)
+	return q
+}
+
+func (q *sinkQueue) run() {
+	for {
+		select {
+		case <-q.stopCh:
+			return
+		case entry := <-q.ch:
+			if err := q.sink.Write(entry.line, entry.ts); err != nil {
+				q.logger.Warn("log sink write failed", "sink", q.name, "error", err)
+			}
+		}
+	}
+}
+
+// enqueue drops the oldest queued line to make room when the queue is full,
+// rather than blocking the caller.
+func (q *sinkQueue) enqueue(entry logLine) {
+	select {
+	case q.ch <- entry:
+		return
+	default:
+	}
+	select {
+	case <-q.ch:
+		q.dropped.Add(1)
+	default:
+	}
+	select {
+	case q.ch <- entry:
+	default:
+		q.dropped.Add(1)
+	}
+}
+
+func (q *sinkQueue) droppedCount() uint64 {
+	return q.dropped.Load()
+}
+
+func (q *sinkQueue) close() {
+	close(q.stopCh)
+	q.wg.Wait()
+	_ = q.sink.Close()
+}
@@ -0,0 +1,211 @@
+package device
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSinkQueueEnqueueDropsOldest(t *testing.T) {
+	// Built directly rather than via newSinkQueue so no run goroutine drains
+	// the channel concurrently, keeping the drop-oldest behavior
+	// deterministic to observe.
+	q := &sinkQueue{name: "test", sink: NewWriterSink(io.Discard), ch: make(chan logLine, 2), stopCh: make(chan struct{})}
+
+	q.enqueue(logLine{line: []byte("1")})
+	q.enqueue(logLine{line: []byte("2")})
+	if got := q.droppedCount(); got != 0 {
+		t.Fatalf("droppedCount() = %d, want 0 before the queue fills", got)
+	}
+
+	q.enqueue(logLine{line: []byte("3")})
+	if got := q.droppedCount(); got != 1 {
+		t.Fatalf("droppedCount() = %d, want 1 after enqueuing past capacity", got)
+	}
+
+	var got []string
+	for i := 0; i < 2; i++ {
+		got = append(got, string((<-q.ch).line))
+	}
+	want := []string{"2", "3"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("queued entry %d = %q, want %q (oldest entry should have been dropped to make room)", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFileSinkRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "device.log")
+	sink, err := NewFileSink(FileSinkConfig{Path: path})
+	if err != nil {
+		t.Fatalf("NewFileSink: %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.Write([]byte("line one"), time.Now()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := sink.rotate(); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+
+	backups, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("backups after rotate = %d, want 1", len(backups))
+	}
+	if data, err := os.ReadFile(backups[0]); err != nil || !bytes.Contains(data, []byte("line one")) {
+		t.Errorf("rotated file contents = %q, err %v, want it to contain the pre-rotate write", data, err)
+	}
+
+	if err := sink.Write([]byte("line two"), time.Now()); err != nil {
+		t.Fatalf("Write to post-rotate file: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if bytes.Contains(data, []byte("line one")) || !bytes.Contains(data, []byte("line two")) {
+		t.Errorf("current log file = %q, want only the post-rotate write", data)
+	}
+}
+
+func TestFileSinkPruneBackupsByCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "device.log")
+	sink, err := NewFileSink(FileSinkConfig{Path: path, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("NewFileSink: %v", err)
+	}
+	defer sink.Close()
+
+	for _, suffix := range []string{"a", "b", "c"} {
+		if err := os.WriteFile(path+"."+suffix, []byte("old"), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	sink.pruneBackups()
+
+	backups, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	sort.Strings(backups)
+	want := []string{path + ".b", path + ".c"}
+	if len(backups) != len(want) {
+		t.Fatalf("backups after pruning = %v, want %v", backups, want)
+	}
+	for i := range want {
+		if backups[i] != want[i] {
+			t.Errorf("backups[%d] = %q, want %q (pruneBackups should keep the lexically-newest names)", i, backups[i], want[i])
+		}
+	}
+}
+
+func TestFileSinkPruneBackupsByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "device.log")
+	sink, err := NewFileSink(FileSinkConfig{Path: path, MaxAgeDays: 1})
+	if err != nil {
+		t.Fatalf("NewFileSink: %v", err)
+	}
+	defer sink.Close()
+
+	oldName, newName := path+".old", path+".new"
+	if err := os.WriteFile(oldName, []byte("old"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	oldTime := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(oldName, oldTime, oldTime); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+	if err := os.WriteFile(newName, []byte("new"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	sink.pruneBackups()
+
+	if _, err := os.Stat(oldName); !os.IsNotExist(err) {
+		t.Errorf("backup older than MaxAgeDays should have been pruned, stat err = %v", err)
+	}
+	if _, err := os.Stat(newName); err != nil {
+		t.Errorf("recent backup should not have been pruned: %v", err)
+	}
+}
+
+func newTestHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{
+		cfg:    HTTPSinkConfig{URL: url},
+		client: http.DefaultClient,
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		stopCh: make(chan struct{}),
+	}
+}
+
+func TestHTTPSinkPostWithRetry(t *testing.T) {
+	t.Run("succeeds immediately on 200", func(t *testing.T) {
+		var gotBody []httpLogLine
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&gotBody)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		sink := newTestHTTPSink(srv.URL)
+		if err := sink.postWithRetry([]httpLogLine{{Line: "hello", Time: time.Now()}}); err != nil {
+			t.Fatalf("postWithRetry: %v", err)
+		}
+		if len(gotBody) != 1 || gotBody[0].Line != "hello" {
+			t.Errorf("server received %+v, want one line %q", gotBody, "hello")
+		}
+	})
+
+	t.Run("retries on failure then succeeds", func(t *testing.T) {
+		var attempts atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) < 2 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		sink := newTestHTTPSink(srv.URL)
+		if err := sink.postWithRetry([]httpLogLine{{Line: "x", Time: time.Now()}}); err != nil {
+			t.Fatalf("postWithRetry: %v", err)
+		}
+		if got := attempts.Load(); got != 2 {
+			t.Errorf("server saw %d attempts, want 2 (one failure, then a retry that succeeds)", got)
+		}
+	})
+
+	t.Run("gives up after httpSinkMaxRetries", func(t *testing.T) {
+		var attempts atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		sink := newTestHTTPSink(srv.URL)
+		err := sink.postWithRetry([]httpLogLine{{Line: "x", Time: time.Now()}})
+		if err == nil {
+			t.Fatal("postWithRetry() = nil error, want an error after exhausting retries")
+		}
+		if got := attempts.Load(); got != httpSinkMaxRetries {
+			t.Errorf("server saw %d attempts, want httpSinkMaxRetries (%d)", got, httpSinkMaxRetries)
+		}
+	})
+}
@@ -0,0 +1,235 @@
+// Package layout translates UTF-8 text and chord strings (e.g. "ctrl+alt+t")
+// into USB HID Usage IDs and modifier bytes, for keyboard layouts commonly
+// found on physical keyboards.
+package layout
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Name identifies a supported keyboard layout.
+type Name string
+
+const (
+	US Name = "us"
+	UK Name = "uk"
+	DE Name = "de"
+
+	// Default is used when a caller does not specify a layout.
+	Default Name = US
+)
+
+// USB HID modifier byte bits (Keyboard/Keypad page).
+const (
+	ModLeftCtrl   byte = 0x01
+	ModLeftShift  byte = 0x02
+	ModLeftAlt    byte = 0x04
+	ModLeftGUI    byte = 0x08
+	ModRightCtrl  byte = 0x10
+	ModRightShift byte = 0x20
+	ModRightAlt   byte = 0x40
+	ModRightGUI   byte = 0x80
+)
+
+// USB HID Usage IDs (Keyboard/Keypad page) for keys referenced by name.
+const (
+	codeEnter     byte = 0x28
+	codeEscape    byte = 0x29
+	codeBackspace byte = 0x2A
+	codeTab       byte = 0x2B
+	codeSpace     byte = 0x2C
+	codeCapsLock  byte = 0x39
+	codeRight     byte = 0x4F
+	codeLeft      byte = 0x50
+	codeDown      byte = 0x51
+	codeUp        byte = 0x52
+	codeInsert    byte = 0x49
+	codeHome      byte = 0x4A
+	codePageUp    byte = 0x4B
+	codeDelete    byte = 0x4C
+	codeEnd       byte = 0x4D
+	codePageDown  byte = 0x4E
+)
+
+var namedKeys = map[string]byte{
+	"enter":     codeEnter,
+	"return":    codeEnter,
+	"esc":       codeEscape,
+	"escape":    codeEscape,
+	"backspace": codeBackspace,
+	"tab":       codeTab,
+	"space":     codeSpace,
+	"capslock":  codeCapsLock,
+	"right":     codeRight,
+	"left":      codeLeft,
+	"down":      codeDown,
+	"up":        codeUp,
+	"insert":    codeInsert,
+	"home":      codeHome,
+	"pageup":    codePageUp,
+	"delete":    codeDelete,
+	"del":       codeDelete,
+	"end":       codeEnd,
+	"pagedown":  codePageDown,
+}
+
+func init() {
+	for i := 0; i < 12; i++ {
+		namedKeys[fmt.Sprintf("f%d", i+1)] = byte(0x3A + i)
+	}
+}
+
+var modifierAliases = map[string]byte{
+	"ctrl":       ModLeftCtrl,
+	"control":    ModLeftCtrl,
+	"leftctrl":   ModLeftCtrl,
+	"rightctrl":  ModRightCtrl,
+	"shift":      ModLeftShift,
+	"leftshift":  ModLeftShift,
+	"rightshift": ModRightShift,
+	"alt":        ModLeftAlt,
+	"leftalt":    ModLeftAlt,
+	"rightalt":   ModRightAlt,
+	"altgr":      ModRightAlt,
+	"gui":        ModLeftGUI,
+	"win":        ModLeftGUI,
+	"cmd":        ModLeftGUI,
+	"leftgui":    ModLeftGUI,
+	"rightgui":   ModRightGUI,
+}
+
+// key describes how a rune is produced on a given layout: the base HID
+// Usage ID, plus whether Shift or AltGr (Right Alt) must be held.
+type key struct {
+	code  byte
+	shift bool
+	altGr bool
+}
+
+// baseLayout covers the ASCII range shared by all supported layouts; each
+// named layout below only needs to list its differences.
+var baseLayout = buildBaseLayout()
+
+func buildBaseLayout() map[rune]key {
+	m := make(map[rune]key)
+	for r := 'a'; r <= 'z'; r++ {
+		m[r] = key{code: byte(0x04 + (r - 'a'))}
+		m[r-32] = key{code: byte(0x04 + (r - 'a')), shift: true}
+	}
+	digits := []rune("1234567890")
+	for i, r := range digits {
+		m[r] = key{code: byte(0x1E + i)}
+	}
+	shiftedDigits := []rune("!@#$%^&*()")
+	for i, r := range shiftedDigits {
+		m[r] = key{code: byte(0x1E + i), shift: true}
+	}
+	m['\n'] = key{code: codeEnter}
+	m['\t'] = key{code: codeTab}
+	m[' '] = key{code: codeSpace}
+	plain := map[rune]byte{
+		'-': 0x2D, '=': 0x2E, '[': 0x2F, ']': 0x30, '\\': 0x31,
+		';': 0x33, '\'': 0x34, '`': 0x35, ',': 0x36, '.': 0x37, '/': 0x38,
+	}
+	for r, code := range plain {
+		m[r] = key{code: code}
+	}
+	shifted := map[rune]byte{
+		'_': 0x2D, '+': 0x2E, '{': 0x2F, '}': 0x30, '|': 0x31,
+		':': 0x33, '"': 0x34, '~': 0x35, '<': 0x36, '>': 0x37, '?': 0x38,
+	}
+	for r, code := range shifted {
+		m[r] = key{code: code, shift: true}
+	}
+	return m
+}
+
+// ukOverrides lists the runes that differ from the US layout on a UK
+// (ISO) keyboard: " and @ swap keys, # and ~ sit on their own key, and \
+// and | move to the extra key beside left Shift.
+var ukOverrides = map[rune]key{
+	'"':  {code: 0x1F, shift: true}, // shift+2
+	'@':  {code: 0x34, shift: true}, // shift+'
+	'#':  {code: 0x32},
+	'~':  {code: 0x32, shift: true},
+	'\\': {code: 0x64},
+	'|':  {code: 0x64, shift: true},
+}
+
+// deOverrides lists the runes that differ from the US layout on a German
+// (QWERTZ) keyboard: y/z are swapped and several symbols move to AltGr.
+var deOverrides = map[rune]key{
+	'z': {code: 0x1C},
+	'Z': {code: 0x1C, shift: true},
+	'y': {code: 0x1D},
+	'Y': {code: 0x1D, shift: true},
+	'ü': {code: 0x2F},
+	'ö': {code: 0x33},
+	'ä': {code: 0x34},
+	'ß': {code: 0x2D},
+	'@': {code: 0x1F, altGr: true}, // altgr+2
+	'[': {code: 0x25, altGr: true}, // altgr+8
+	']': {code: 0x26, altGr: true}, // altgr+9
+}
+
+// Translate maps a single rune to a HID Usage ID and modifier byte for the
+// given layout. An unsupported rune returns an error.
+func Translate(layoutName Name, r rune) (code byte, modifier byte, err error) {
+	k, ok := lookup(layoutName, r)
+	if !ok {
+		return 0, 0, fmt.Errorf("layout %q: unsupported rune %q", layoutName, r)
+	}
+	if k.shift {
+		modifier |= ModLeftShift
+	}
+	if k.altGr {
+		modifier |= ModRightAlt
+	}
+	return k.code, modifier, nil
+}
+
+func lookup(layoutName Name, r rune) (key, bool) {
+	switch layoutName {
+	case UK:
+		if k, ok := ukOverrides[r]; ok {
+			return k, true
+		}
+	case DE:
+		if k, ok := deOverrides[r]; ok {
+			return k, true
+		}
+	}
+	k, ok := baseLayout[r]
+	return k, ok
+}
+
+// ParseChord parses a chord string such as "ctrl+alt+t" or "enter" into a HID
+// Usage ID and modifier byte. The final '+'-separated token is the key
+// itself; every preceding token must name a modifier.
+func ParseChord(chord string) (code byte, modifier byte, err error) {
+	parts := strings.Split(chord, "+")
+	if len(parts) == 0 || parts[len(parts)-1] == "" {
+		return 0, 0, fmt.Errorf("invalid chord %q", chord)
+	}
+	for _, part := range parts[:len(parts)-1] {
+		bit, ok := modifierAliases[strings.ToLower(strings.TrimSpace(part))]
+		if !ok {
+			return 0, 0, fmt.Errorf("invalid chord %q: unknown modifier %q", chord, part)
+		}
+		modifier |= bit
+	}
+	base := strings.TrimSpace(parts[len(parts)-1])
+	if namedCode, ok := namedKeys[strings.ToLower(base)]; ok {
+		return namedCode, modifier, nil
+	}
+	runes := []rune(base)
+	if len(runes) != 1 {
+		return 0, 0, fmt.Errorf("invalid chord %q: unknown key %q", chord, base)
+	}
+	baseCode, baseModifier, err := Translate(Default, runes[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid chord %q: %w", chord, err)
+	}
+	return baseCode, modifier | baseModifier, nil
+}
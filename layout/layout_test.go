@@ -0,0 +1,81 @@
+package layout
+
+import "testing"
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name     string
+		layout   Name
+		r        rune
+		wantCode byte
+		wantMod  byte
+		wantErr  bool
+	}{
+		{name: "us lowercase", layout: US, r: 'a', wantCode: 0x04},
+		{name: "us uppercase sets shift", layout: US, r: 'A', wantCode: 0x04, wantMod: ModLeftShift},
+		{name: "us digit", layout: US, r: '1', wantCode: 0x1E},
+		{name: "us shifted digit sets shift", layout: US, r: '!', wantCode: 0x1E, wantMod: ModLeftShift},
+		{name: "default layout falls back to base", layout: Default, r: 'z', wantCode: 0x1D},
+		{name: "uk override", layout: UK, r: '"', wantCode: 0x1F, wantMod: ModLeftShift},
+		{name: "uk falls back to base for unaffected rune", layout: UK, r: 'a', wantCode: 0x04},
+		{name: "de swaps y and z", layout: DE, r: 'z', wantCode: 0x1C},
+		{name: "de altgr symbol sets right alt", layout: DE, r: '@', wantCode: 0x1F, wantMod: ModRightAlt},
+		{name: "unsupported rune errors", layout: US, r: '€', wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, modifier, err := Translate(tt.layout, tt.r)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Translate(%q, %q) = nil error, want error", tt.layout, tt.r)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Translate(%q, %q) returned error: %v", tt.layout, tt.r, err)
+			}
+			if code != tt.wantCode || modifier != tt.wantMod {
+				t.Errorf("Translate(%q, %q) = (0x%02X, 0x%02X), want (0x%02X, 0x%02X)",
+					tt.layout, tt.r, code, modifier, tt.wantCode, tt.wantMod)
+			}
+		})
+	}
+}
+
+func TestParseChord(t *testing.T) {
+	tests := []struct {
+		name     string
+		chord    string
+		wantCode byte
+		wantMod  byte
+		wantErr  bool
+	}{
+		{name: "named key", chord: "enter", wantCode: codeEnter},
+		{name: "single modifier plus letter", chord: "ctrl+a", wantCode: 0x04, wantMod: ModLeftCtrl},
+		{name: "multiple modifiers", chord: "ctrl+alt+t", wantCode: 0x17, wantMod: ModLeftCtrl | ModLeftAlt},
+		{name: "modifier is case-insensitive", chord: "CTRL+a", wantCode: 0x04, wantMod: ModLeftCtrl},
+		{name: "modifier and key combine bits", chord: "shift+1", wantCode: 0x1E, wantMod: ModLeftShift},
+		{name: "unknown modifier errors", chord: "foo+a", wantErr: true},
+		{name: "multi-rune unnamed key errors", chord: "ctrl+ab", wantErr: true},
+		{name: "empty chord errors", chord: "", wantErr: true},
+		{name: "trailing separator errors", chord: "ctrl+", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, modifier, err := ParseChord(tt.chord)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseChord(%q) = nil error, want error", tt.chord)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseChord(%q) returned error: %v", tt.chord, err)
+			}
+			if code != tt.wantCode || modifier != tt.wantMod {
+				t.Errorf("ParseChord(%q) = (0x%02X, 0x%02X), want (0x%02X, 0x%02X)",
+					tt.chord, code, modifier, tt.wantCode, tt.wantMod)
+			}
+		})
+	}
+}